@@ -0,0 +1,54 @@
+package victorops
+
+import (
+	"math/rand"
+	"time"
+)
+
+// stopBackoff is returned by backoff.next once the retry budget has been
+// exhausted.
+const stopBackoff time.Duration = -1
+
+// backoff produces a sequence of jittered, exponentially increasing
+// intervals bounded by a total elapsed-time budget. It mirrors the
+// semantics of cenkalti/backoff's ExponentialBackOff closely enough for
+// our retry loop without pulling in the dependency.
+type backoff struct {
+	initialInterval time.Duration
+	multiplier      float64
+	maxElapsed      time.Duration
+
+	current time.Duration
+	start   time.Time
+}
+
+func newBackoff(initialInterval time.Duration, multiplier float64, maxElapsed time.Duration) *backoff {
+	return &backoff{
+		initialInterval: initialInterval,
+		multiplier:      multiplier,
+		maxElapsed:      maxElapsed,
+		current:         initialInterval,
+	}
+}
+
+// next returns the jittered interval to wait before the next retry, or
+// stopBackoff if the maxElapsed budget has been used up.
+func (b *backoff) next() time.Duration {
+	if b.start.IsZero() {
+		b.start = time.Now()
+	}
+	if b.maxElapsed > 0 && time.Since(b.start) >= b.maxElapsed {
+		return stopBackoff
+	}
+
+	interval := b.current
+	if b.multiplier > 0 {
+		b.current = time.Duration(float64(b.current) * b.multiplier)
+	}
+
+	if interval <= 0 {
+		return 0
+	}
+	// Full jitter: a random duration in [0, interval).
+	return time.Duration(rand.Int63n(int64(interval)))
+}
@@ -0,0 +1,48 @@
+package victorops
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffNext_Jitter(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, 2, 0)
+
+	for i, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		got := b.next()
+		if got < 0 || got >= want {
+			t.Fatalf("next() #%d = %v, want in [0, %v)", i, got, want)
+		}
+	}
+}
+
+func TestBackoffNext_ZeroMultiplierDoesNotGrow(t *testing.T) {
+	b := newBackoff(50*time.Millisecond, 0, 0)
+
+	for i := 0; i < 3; i++ {
+		got := b.next()
+		if got < 0 || got >= 50*time.Millisecond {
+			t.Fatalf("next() #%d = %v, want in [0, 50ms)", i, got)
+		}
+	}
+}
+
+func TestBackoffNext_StopsAfterMaxElapsed(t *testing.T) {
+	b := newBackoff(time.Millisecond, 2, 10*time.Millisecond)
+	b.start = time.Now().Add(-time.Hour)
+
+	if got := b.next(); got != stopBackoff {
+		t.Fatalf("next() = %v, want stopBackoff", got)
+	}
+}
+
+func TestBackoffNext_SetsStartOnFirstCall(t *testing.T) {
+	b := newBackoff(time.Millisecond, 2, time.Minute)
+	if !b.start.IsZero() {
+		t.Fatal("start should be zero before the first call to next()")
+	}
+	b.next()
+	if b.start.IsZero() {
+		t.Fatal("start should be set after the first call to next()")
+	}
+}
@@ -0,0 +1,65 @@
+package victorops
+
+import "time"
+
+// Config is the configuration for the victorops service.
+type Config struct {
+	// Whether VictorOps integration is enabled.
+	Enabled bool `toml:"enabled" override:"enabled"`
+	// The VictorOps API key.
+	APIKey string `toml:"api-key" override:"api-key,redact"`
+	// The default routing key to use if one is not specified per alert.
+	RoutingKey string `toml:"routing-key" override:"routing-key"`
+	// The VictorOps API URL, usually the default is all that is needed.
+	URL string `toml:"url" override:"url"`
+	// Whether all alerts should automatically post to VictorOps.
+	Global bool `toml:"global" override:"global"`
+	// Timeout for a single POST attempt to VictorOps.
+	Timeout time.Duration `toml:"timeout" override:"timeout"`
+	// The interval before the first retry attempt.
+	RetryInitialInterval time.Duration `toml:"retry-initial-interval" override:"retry-initial-interval"`
+	// The multiplier applied to the retry interval after each attempt.
+	RetryMultiplier float64 `toml:"retry-multiplier" override:"retry-multiplier"`
+	// The maximum total time to spend retrying before giving up.
+	RetryMaxElapsed time.Duration `toml:"retry-max-elapsed" override:"retry-max-elapsed"`
+	// Whether to buffer and batch alerts instead of posting each one as it arrives.
+	Batch bool `toml:"batch" override:"batch"`
+	// The number of queued alerts that triggers an immediate flush.
+	BatchSize int `toml:"batch-size" override:"batch-size"`
+	// The maximum time a partially-filled batch waits before being flushed.
+	BatchInterval time.Duration `toml:"batch-interval" override:"batch-interval"`
+	// The maximum number of distinct (entity, routing key) pairs that may be queued awaiting a flush.
+	QueueSize int `toml:"queue-size" override:"queue-size"`
+	// What to do when the queue is full: "dropOldest", "dropNewest", or "block".
+	OverflowPolicy string `toml:"overflow-policy" override:"overflow-policy"`
+	// Per-tag routing rules, evaluated in order, that resolve an alert to
+	// one or more VictorOps routing keys instead of the single global
+	// RoutingKey. Declared as repeated [[victorops.routing]] TOML blocks.
+	Routing []RoutingConfig `toml:"routing" override:"routing"`
+}
+
+// RoutingConfig is one [[victorops.routing]] rule. An alert matches the
+// rule when its tags[Tag] == Value, or when Value is empty and the alert
+// merely carries Tag. A match contributes either the literal RoutingKey,
+// or the result of executing Template against the alert's tags.
+type RoutingConfig struct {
+	Tag        string `toml:"tag" override:"tag"`
+	Value      string `toml:"value" override:"value"`
+	RoutingKey string `toml:"routing-key" override:"routing-key"`
+	Template   string `toml:"template" override:"template"`
+}
+
+// NewConfig returns a new Config with its default values set.
+func NewConfig() Config {
+	return Config{
+		URL:                  "https://alert.victorops.com/integrations/generic/20131114/alert",
+		Timeout:              30 * time.Second,
+		RetryInitialInterval: 500 * time.Millisecond,
+		RetryMultiplier:      1.5,
+		RetryMaxElapsed:      time.Minute,
+		BatchSize:            100,
+		BatchInterval:        time.Second,
+		QueueSize:            1000,
+		OverflowPolicy:       "dropOldest",
+	}
+}
@@ -0,0 +1,91 @@
+package victorops
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/influxdata/kapacitor/services/httpd"
+)
+
+// metricsPattern and testRoutingPattern are mounted under the admin
+// router's service prefix, so they are reachable at
+// /kapacitor/v1/services/victorops/metrics and .../routing/test.
+const (
+	metricsPattern     = "/services/victorops/metrics"
+	testRoutingPattern = "/services/victorops/routing/test"
+)
+
+// HTTPDService is the subset of httpd.Service that victorops needs in
+// order to expose its admin routes.
+type HTTPDService interface {
+	AddRoutes([]httpd.Route) error
+	DelRoutes([]httpd.Route)
+}
+
+// SetHTTPDService wires the admin HTTP router into the service, so Open
+// can register its routes against it.
+func (s *Service) SetHTTPDService(h HTTPDService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpdService = h
+}
+
+func (s *Service) routes() []httpd.Route {
+	return []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     metricsPattern,
+			HandlerFunc: s.handleMetrics,
+		},
+		{
+			Method:      "POST",
+			Pattern:     testRoutingPattern,
+			HandlerFunc: s.handleTestRouting,
+		},
+	}
+}
+
+// handleMetrics renders the current per-endpoint POST metrics in
+// Prometheus text exposition format.
+func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.WriteMetrics(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type testRoutingRequest struct {
+	Tags map[string]string `json:"tags"`
+}
+
+type testRoutingResponse struct {
+	RoutingKeys []string `json:"routingKeys"`
+}
+
+// handleTestRouting resolves a sample tag set against the configured
+// RoutingKeyResolver, so operators can check [[victorops.routing]] rules
+// without having to trigger a real alert.
+func (s *Service) handleTestRouting(w http.ResponseWriter, r *http.Request) {
+	var req testRoutingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	resolver := s.routingResolver
+	s.mu.RUnlock()
+
+	var keys []string
+	if resolver != nil {
+		resolved, err := resolver.Resolve(req.Tags)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		keys = resolved
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(testRoutingResponse{RoutingKeys: keys})
+}
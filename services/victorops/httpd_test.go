@@ -0,0 +1,92 @@
+package victorops
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleMetrics_ServesPrometheusExposition(t *testing.T) {
+	s := NewService(NewConfig(), testLogger())
+	s.metrics.observe("teamA", "CRITICAL", 10*time.Millisecond, 200)
+
+	req := httptest.NewRequest("GET", metricsPattern, nil)
+	w := httptest.NewRecorder()
+	s.handleMetrics(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("Content-Type = %q, want text/plain", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `routing_key="teamA"`) {
+		t.Fatalf("body missing teamA series, got:\n%s", body)
+	}
+}
+
+func TestHandleTestRouting_ResolvesTags(t *testing.T) {
+	s := NewService(NewConfig(), testLogger())
+	router, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetRoutingKeyResolver(router)
+
+	body, _ := json.Marshal(testRoutingRequest{Tags: map[string]string{"team": "db"}})
+	req := httptest.NewRequest("POST", testRoutingPattern, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	s.handleTestRouting(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var got testRoutingResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dbTeam"}; len(got.RoutingKeys) != 1 || got.RoutingKeys[0] != want[0] {
+		t.Fatalf("RoutingKeys = %v, want %v", got.RoutingKeys, want)
+	}
+}
+
+func TestHandleTestRouting_NoResolverConfigured(t *testing.T) {
+	s := NewService(NewConfig(), testLogger())
+	s.SetRoutingKeyResolver(nil)
+
+	body, _ := json.Marshal(testRoutingRequest{Tags: map[string]string{"team": "db"}})
+	req := httptest.NewRequest("POST", testRoutingPattern, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	s.handleTestRouting(w, req)
+
+	if w.Result().StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", w.Result().StatusCode)
+	}
+	var got testRoutingResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.RoutingKeys) != 0 {
+		t.Fatalf("RoutingKeys = %v, want none without a configured resolver", got.RoutingKeys)
+	}
+}
+
+func TestHandleTestRouting_MalformedJSONReturns400(t *testing.T) {
+	s := NewService(NewConfig(), testLogger())
+
+	req := httptest.NewRequest("POST", testRoutingPattern, strings.NewReader("{not json"))
+	w := httptest.NewRecorder()
+	s.handleTestRouting(w, req)
+
+	if w.Result().StatusCode != 400 {
+		t.Fatalf("status = %d, want 400 for malformed JSON", w.Result().StatusCode)
+	}
+}
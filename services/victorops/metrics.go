@@ -0,0 +1,198 @@
+package victorops
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the histogram buckets
+// used to track POST latency, matching Prometheus's "le" bucket convention.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// endpointKey identifies one (routing_key, message_type) pair for labeling
+// metrics, mirroring the dimensions operators care about when a specific
+// team's notifications start silently failing.
+type endpointKey struct {
+	routingKey  string
+	messageType string
+}
+
+// endpointStats holds the counters and latency histogram for a single
+// endpointKey.
+type endpointStats struct {
+	mu          sync.Mutex
+	attempts    int64
+	successes   int64
+	clientErrs  int64
+	serverErrs  int64
+	bucketCount []int64 // cumulative counts, parallel to latencyBuckets
+	sum         float64
+	count       int64
+}
+
+func newEndpointStats() *endpointStats {
+	return &endpointStats{
+		bucketCount: make([]int64, len(latencyBuckets)),
+	}
+}
+
+// observe records the outcome of a single POST attempt: its latency and
+// either success, a 4xx, a 5xx, or a transport-level failure (statusCode
+// of 0).
+func (e *endpointStats) observe(latency time.Duration, statusCode int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.attempts++
+	switch {
+	case statusCode == 0 || statusCode >= 500:
+		e.serverErrs++
+	case statusCode >= 400:
+		e.clientErrs++
+	default:
+		e.successes++
+	}
+
+	seconds := latency.Seconds()
+	e.sum += seconds
+	e.count++
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			e.bucketCount[i]++
+		}
+	}
+}
+
+// snapshot is a point-in-time, immutable copy of an endpointStats, safe to
+// read without holding any lock.
+type snapshot struct {
+	key         endpointKey
+	attempts    int64
+	successes   int64
+	clientErrs  int64
+	serverErrs  int64
+	bucketCount []int64
+	sum         float64
+	count       int64
+}
+
+func (e *endpointStats) snapshot(key endpointKey) snapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	buckets := make([]int64, len(e.bucketCount))
+	copy(buckets, e.bucketCount)
+	return snapshot{
+		key:         key,
+		attempts:    e.attempts,
+		successes:   e.successes,
+		clientErrs:  e.clientErrs,
+		serverErrs:  e.serverErrs,
+		bucketCount: buckets,
+		sum:         e.sum,
+		count:       e.count,
+	}
+}
+
+// metricsRegistry tracks per-endpoint POST metrics for the lifetime of the
+// service.
+type metricsRegistry struct {
+	mu        sync.Mutex
+	endpoints map[endpointKey]*endpointStats
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		endpoints: make(map[endpointKey]*endpointStats),
+	}
+}
+
+func (m *metricsRegistry) observe(routingKey, messageType string, latency time.Duration, statusCode int) {
+	key := endpointKey{routingKey: routingKey, messageType: messageType}
+
+	m.mu.Lock()
+	stats, ok := m.endpoints[key]
+	if !ok {
+		stats = newEndpointStats()
+		m.endpoints[key] = stats
+	}
+	m.mu.Unlock()
+
+	stats.observe(latency, statusCode)
+}
+
+// Snapshot returns a stable, sorted copy of all per-endpoint metrics
+// currently recorded.
+func (m *metricsRegistry) Snapshot() []snapshot {
+	m.mu.Lock()
+	keys := make([]endpointKey, 0, len(m.endpoints))
+	stats := make([]*endpointStats, 0, len(m.endpoints))
+	for k, s := range m.endpoints {
+		keys = append(keys, k)
+		stats = append(stats, s)
+	}
+	m.mu.Unlock()
+
+	snapshots := make([]snapshot, len(keys))
+	for i, k := range keys {
+		snapshots[i] = stats[i].snapshot(k)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].key.routingKey != snapshots[j].key.routingKey {
+			return snapshots[i].key.routingKey < snapshots[j].key.routingKey
+		}
+		return snapshots[i].key.messageType < snapshots[j].key.messageType
+	})
+	return snapshots
+}
+
+// Metrics returns a snapshot of the per-endpoint POST counters and latency
+// histograms recorded so far.
+func (s *Service) Metrics() []snapshot {
+	return s.metrics.Snapshot()
+}
+
+// WriteMetrics renders the current metrics as Prometheus text exposition
+// format, suitable for serving from an HTTP handler.
+func (s *Service) WriteMetrics(w io.Writer) error {
+	snapshots := s.Metrics()
+
+	fmt.Fprintln(w, "# HELP victorops_post_attempts_total Total VictorOps POST attempts.")
+	fmt.Fprintln(w, "# TYPE victorops_post_attempts_total counter")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "victorops_post_attempts_total{routing_key=%q,message_type=%q} %d\n", s.key.routingKey, s.key.messageType, s.attempts)
+	}
+
+	fmt.Fprintln(w, "# HELP victorops_post_success_total Successful VictorOps POSTs.")
+	fmt.Fprintln(w, "# TYPE victorops_post_success_total counter")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "victorops_post_success_total{routing_key=%q,message_type=%q} %d\n", s.key.routingKey, s.key.messageType, s.successes)
+	}
+
+	fmt.Fprintln(w, "# HELP victorops_post_client_errors_total VictorOps POSTs that received a 4xx response.")
+	fmt.Fprintln(w, "# TYPE victorops_post_client_errors_total counter")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "victorops_post_client_errors_total{routing_key=%q,message_type=%q} %d\n", s.key.routingKey, s.key.messageType, s.clientErrs)
+	}
+
+	fmt.Fprintln(w, "# HELP victorops_post_server_errors_total VictorOps POSTs that failed at the network level or received a 5xx response.")
+	fmt.Fprintln(w, "# TYPE victorops_post_server_errors_total counter")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "victorops_post_server_errors_total{routing_key=%q,message_type=%q} %d\n", s.key.routingKey, s.key.messageType, s.serverErrs)
+	}
+
+	fmt.Fprintln(w, "# HELP victorops_post_latency_seconds VictorOps POST latency.")
+	fmt.Fprintln(w, "# TYPE victorops_post_latency_seconds histogram")
+	for _, s := range snapshots {
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "victorops_post_latency_seconds_bucket{routing_key=%q,message_type=%q,le=%q} %d\n", s.key.routingKey, s.key.messageType, fmt.Sprintf("%g", le), s.bucketCount[i])
+		}
+		fmt.Fprintf(w, "victorops_post_latency_seconds_bucket{routing_key=%q,message_type=%q,le=\"+Inf\"} %d\n", s.key.routingKey, s.key.messageType, s.count)
+		fmt.Fprintf(w, "victorops_post_latency_seconds_sum{routing_key=%q,message_type=%q} %g\n", s.key.routingKey, s.key.messageType, s.sum)
+		fmt.Fprintf(w, "victorops_post_latency_seconds_count{routing_key=%q,message_type=%q} %d\n", s.key.routingKey, s.key.messageType, s.count)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package victorops
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsRegistryObserve_TracksPerEndpointCounters(t *testing.T) {
+	m := newMetricsRegistry()
+
+	m.observe("teamA", "CRITICAL", 10*time.Millisecond, 200)
+	m.observe("teamA", "CRITICAL", 10*time.Millisecond, 503)
+	m.observe("teamA", "CRITICAL", 10*time.Millisecond, 404)
+	m.observe("teamB", "WARNING", 10*time.Millisecond, 200)
+
+	snapshots := m.Snapshot()
+	if len(snapshots) != 2 {
+		t.Fatalf("len(snapshots) = %d, want 2 distinct endpoints", len(snapshots))
+	}
+
+	// Snapshot sorts by (routingKey, messageType), so teamA sorts first.
+	teamA := snapshots[0]
+	if teamA.key.routingKey != "teamA" || teamA.key.messageType != "CRITICAL" {
+		t.Fatalf("snapshots[0].key = %+v, want teamA/CRITICAL", teamA.key)
+	}
+	if teamA.attempts != 3 {
+		t.Fatalf("teamA.attempts = %d, want 3", teamA.attempts)
+	}
+	if teamA.successes != 1 {
+		t.Fatalf("teamA.successes = %d, want 1", teamA.successes)
+	}
+	if teamA.serverErrs != 1 {
+		t.Fatalf("teamA.serverErrs = %d, want 1 (the 503)", teamA.serverErrs)
+	}
+	if teamA.clientErrs != 1 {
+		t.Fatalf("teamA.clientErrs = %d, want 1 (the 404)", teamA.clientErrs)
+	}
+}
+
+func TestEndpointStatsObserve_ClassifiesTransportFailureAsServerErr(t *testing.T) {
+	e := newEndpointStats()
+	e.observe(time.Millisecond, 0)
+
+	snap := e.snapshot(endpointKey{})
+	if snap.serverErrs != 1 {
+		t.Fatalf("serverErrs = %d, want 1 (statusCode 0 is a transport-level failure)", snap.serverErrs)
+	}
+}
+
+func TestEndpointStatsObserve_BucketMath(t *testing.T) {
+	e := newEndpointStats()
+	e.observe(50*time.Millisecond, 200) // falls in every bucket from 0.1s up
+	e.observe(3*time.Second, 200)       // falls only in the 5s and 10s buckets
+	e.observe(20*time.Second, 200)      // exceeds every bucket, only +Inf counts it
+
+	snap := e.snapshot(endpointKey{})
+	if snap.count != 3 {
+		t.Fatalf("count = %d, want 3", snap.count)
+	}
+	// latencyBuckets = [0.1, 0.25, 0.5, 1, 2.5, 5, 10]
+	wantCumulative := []int64{1, 1, 1, 1, 1, 2, 2}
+	for i, want := range wantCumulative {
+		if snap.bucketCount[i] != want {
+			t.Fatalf("bucketCount[%d] (le=%v) = %d, want %d", i, latencyBuckets[i], snap.bucketCount[i], want)
+		}
+	}
+}
+
+func TestWriteMetrics_RendersPrometheusExpositionFormat(t *testing.T) {
+	s := NewService(NewConfig(), testLogger())
+	s.metrics.observe("teamA", "CRITICAL", 10*time.Millisecond, 200)
+	s.metrics.observe("teamA", "CRITICAL", 10*time.Millisecond, 503)
+
+	var buf bytes.Buffer
+	if err := s.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE victorops_post_attempts_total counter",
+		`victorops_post_attempts_total{routing_key="teamA",message_type="CRITICAL"} 2`,
+		`victorops_post_success_total{routing_key="teamA",message_type="CRITICAL"} 1`,
+		`victorops_post_server_errors_total{routing_key="teamA",message_type="CRITICAL"} 1`,
+		`victorops_post_client_errors_total{routing_key="teamA",message_type="CRITICAL"} 0`,
+		`victorops_post_latency_seconds_bucket{routing_key="teamA",message_type="CRITICAL",le="+Inf"} 2`,
+		`victorops_post_latency_seconds_count{routing_key="teamA",message_type="CRITICAL"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("WriteMetrics output missing %q; got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMetrics_NoObservationsRendersOnlyHeaders(t *testing.T) {
+	s := NewService(NewConfig(), testLogger())
+
+	var buf bytes.Buffer
+	if err := s.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "routing_key=") {
+		t.Fatalf("WriteMetrics with no observations should emit no series, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,172 @@
+package victorops
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// overflowPolicy determines what happens when a new entity arrives and the
+// queue is already at capacity.
+type overflowPolicy int
+
+const (
+	// DropOldest evicts the longest-queued entity to make room.
+	DropOldest overflowPolicy = iota
+	// DropNewest discards the incoming entity, leaving the queue untouched.
+	DropNewest
+	// Block waits until space is available, applying backpressure to the
+	// caller of Alert.
+	Block
+)
+
+func parseOverflowPolicy(s string) (overflowPolicy, error) {
+	switch s {
+	case "", "dropOldest":
+		return DropOldest, nil
+	case "dropNewest":
+		return DropNewest, nil
+	case "block":
+		return Block, nil
+	default:
+		return DropOldest, fmt.Errorf("unknown overflow-policy %q", s)
+	}
+}
+
+// messageTypeRank orders VictorOps message types so that coalescing two
+// events for the same entity keeps the more urgent one: a RECOVERY always
+// wins, then CRITICAL, then WARNING.
+var messageTypeRank = map[string]int{
+	"RECOVERY": 3,
+	"CRITICAL": 2,
+	"WARNING":  1,
+}
+
+// alertEvent is a single pending Alert call, held in the queue until it is
+// flushed to VictorOps.
+type alertEvent struct {
+	routingKey  string
+	messageType string
+	message     string
+	entityID    string
+	t           time.Time
+	details     interface{}
+}
+
+func mergeAlertEvent(existing, incoming *alertEvent) *alertEvent {
+	if messageTypeRank[incoming.messageType] >= messageTypeRank[existing.messageType] {
+		return incoming
+	}
+	return existing
+}
+
+// queueKey identifies one pending entry in the queue. Coalescing is scoped
+// to a single routing key so that fanning one alert out to several teams
+// doesn't let one team's event overwrite another's.
+type queueKey struct {
+	entityID   string
+	routingKey string
+}
+
+// queue is a bounded, per-(entity, routing key) coalescing buffer of
+// alertEvents awaiting a batched flush. Events for the same entityID and
+// routingKey collapse to the single most urgent event rather than growing
+// the queue.
+type queue struct {
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	closed   bool
+	events   map[queueKey]*alertEvent
+	ids      []queueKey
+	maxSize  int
+	overflow overflowPolicy
+}
+
+func newQueue(maxSize int, overflow overflowPolicy) *queue {
+	q := &queue{
+		events:   make(map[queueKey]*alertEvent),
+		maxSize:  maxSize,
+		overflow: overflow,
+	}
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds or coalesces e into the queue. It reports whether e was
+// dropped due to the queue being full under DropNewest, or because the
+// queue has been (or becomes, while waiting under Block) closed.
+func (q *queue) push(e *alertEvent) (dropped bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := queueKey{entityID: e.entityID, routingKey: e.routingKey}
+
+	if existing, ok := q.events[key]; ok {
+		if q.closed {
+			return true
+		}
+		q.events[key] = mergeAlertEvent(existing, e)
+		return false
+	}
+
+	for {
+		// Checked unconditionally, not only under Block: a producer
+		// parked here under Block can be woken by a final drain's
+		// broadcast once space exists again, and must not re-queue into
+		// a queue nothing is left to flush.
+		if q.closed {
+			return true
+		}
+		if q.maxSize <= 0 || len(q.ids) < q.maxSize {
+			break
+		}
+		switch q.overflow {
+		case DropNewest:
+			return true
+		case DropOldest:
+			oldest := q.ids[0]
+			q.ids = q.ids[1:]
+			delete(q.events, oldest)
+		case Block:
+			q.notFull.Wait()
+		}
+	}
+
+	q.ids = append(q.ids, key)
+	q.events[key] = e
+	return false
+}
+
+// drain removes and returns all currently queued events, in the order
+// their keys first arrived.
+func (q *queue) drain() []*alertEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events := make([]*alertEvent, 0, len(q.ids))
+	for _, id := range q.ids {
+		events = append(events, q.events[id])
+	}
+	q.ids = nil
+	q.events = make(map[queueKey]*alertEvent)
+	q.notFull.Broadcast()
+	return events
+}
+
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ids)
+}
+
+// close marks the queue closed and unblocks any goroutine waiting in push
+// under the Block policy. Callers must close the queue before signalling
+// or performing their final drain, so a producer woken by that drain's
+// broadcast observes closed and is dropped rather than re-queuing into a
+// queue nothing will ever flush again.
+func (q *queue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.notFull.Broadcast()
+}
@@ -0,0 +1,139 @@
+package victorops
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueuePush_CoalescesSameEntityAndRoutingKey(t *testing.T) {
+	q := newQueue(10, DropOldest)
+
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA", messageType: "WARNING"})
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA", messageType: "CRITICAL"})
+
+	events := q.drain()
+	if len(events) != 1 {
+		t.Fatalf("len(events) = %d, want 1", len(events))
+	}
+	if events[0].messageType != "CRITICAL" {
+		t.Fatalf("messageType = %q, want CRITICAL (more urgent event should win)", events[0].messageType)
+	}
+}
+
+func TestQueuePush_KeepsDistinctRoutingKeysSeparate(t *testing.T) {
+	q := newQueue(10, DropOldest)
+
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA"})
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamB"})
+
+	events := q.drain()
+	if len(events) != 2 {
+		t.Fatalf("len(events) = %d, want 2 (fan-out to distinct teams must not collapse)", len(events))
+	}
+}
+
+func TestQueuePush_DropNewest(t *testing.T) {
+	q := newQueue(1, DropNewest)
+
+	if dropped := q.push(&alertEvent{entityID: "host1", routingKey: "teamA"}); dropped {
+		t.Fatal("first push should not be dropped")
+	}
+	if dropped := q.push(&alertEvent{entityID: "host2", routingKey: "teamA"}); !dropped {
+		t.Fatal("second push should be dropped once the queue is full")
+	}
+
+	events := q.drain()
+	if len(events) != 1 || events[0].entityID != "host1" {
+		t.Fatalf("events = %+v, want only host1 to survive", events)
+	}
+}
+
+func TestQueuePush_DropOldest(t *testing.T) {
+	q := newQueue(1, DropOldest)
+
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA"})
+	if dropped := q.push(&alertEvent{entityID: "host2", routingKey: "teamA"}); dropped {
+		t.Fatal("DropOldest should evict, not drop, the incoming event")
+	}
+
+	events := q.drain()
+	if len(events) != 1 || events[0].entityID != "host2" {
+		t.Fatalf("events = %+v, want only host2 to survive", events)
+	}
+}
+
+func TestQueuePush_BlockWaitsForSpace(t *testing.T) {
+	q := newQueue(1, Block)
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA"})
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- q.push(&alertEvent{entityID: "host2", routingKey: "teamA"})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("push under Block should wait for space, not return immediately")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.drain()
+
+	select {
+	case dropped := <-done:
+		if dropped {
+			t.Fatal("push should succeed once drain frees space")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push blocked under Block never woke after drain")
+	}
+}
+
+func TestQueuePush_ClosedDropsBlockedPush(t *testing.T) {
+	q := newQueue(1, Block)
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA"})
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- q.push(&alertEvent{entityID: "host2", routingKey: "teamA"})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Simulate stopBatching: close the queue, then perform the final drain,
+	// mirroring the order close() documents producers must observe.
+	q.close()
+	q.drain()
+
+	select {
+	case dropped := <-done:
+		if !dropped {
+			t.Fatal("push woken by a drain after close() should be dropped, not re-queued")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push blocked under Block never woke after close")
+	}
+
+	if got := q.len(); got != 0 {
+		t.Fatalf("len() = %d, want 0 (closed queue must stay empty)", got)
+	}
+}
+
+func TestQueuePush_ClosedDropsNewEntries(t *testing.T) {
+	q := newQueue(10, DropOldest)
+	q.close()
+
+	if dropped := q.push(&alertEvent{entityID: "host1", routingKey: "teamA"}); !dropped {
+		t.Fatal("push into a closed queue should report dropped")
+	}
+}
+
+func TestQueueLen(t *testing.T) {
+	q := newQueue(10, DropOldest)
+	q.push(&alertEvent{entityID: "host1", routingKey: "teamA"})
+	q.push(&alertEvent{entityID: "host2", routingKey: "teamA"})
+
+	if got := q.len(); got != 2 {
+		t.Fatalf("len() = %d, want 2", got)
+	}
+}
@@ -0,0 +1,122 @@
+package victorops
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RoutingKeyResolver maps an alert's tags to the VictorOps routing keys it
+// should be delivered to, allowing a single Kapacitor instance to dispatch
+// to many teams without a dedicated handler per team. A nil or empty
+// result means no rule matched and the caller should fall back to the
+// service's default routing key.
+type RoutingKeyResolver interface {
+	Resolve(tags map[string]string) ([]string, error)
+}
+
+// templateData is the value passed to a RoutingConfig's Template.
+type templateData struct {
+	Tags map[string]string
+}
+
+// rule is a single compiled RoutingConfig.
+type rule struct {
+	tag        string
+	value      string
+	routingKey string
+	tmpl       *template.Template
+}
+
+func (r *rule) matches(tags map[string]string) bool {
+	if r.tag == "" {
+		return true
+	}
+	v, ok := tags[r.tag]
+	if !ok {
+		return false
+	}
+	return r.value == "" || v == r.value
+}
+
+func (r *rule) resolve(tags map[string]string) (string, error) {
+	if r.tmpl == nil {
+		return r.routingKey, nil
+	}
+	var buf strings.Builder
+	if err := r.tmpl.Execute(&buf, templateData{Tags: tags}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// tagRouter is a RoutingKeyResolver built from a list of RoutingConfig
+// rules, evaluated in order. All matching rules contribute a routing key,
+// enabling fan-out to multiple teams for a single alert.
+type tagRouter struct {
+	rules []*rule
+}
+
+// newTagRouter compiles cs into a tagRouter. Rules with a Template are
+// parsed with text/template; a malformed template is a configuration
+// error reported immediately rather than at alert time.
+func newTagRouter(cs []RoutingConfig) (*tagRouter, error) {
+	rules := make([]*rule, 0, len(cs))
+	for i, c := range cs {
+		r := &rule{tag: c.Tag, value: c.Value, routingKey: c.RoutingKey}
+		if c.Template != "" {
+			tmpl, err := template.New(fmt.Sprintf("victorops-routing-%d", i)).Parse(c.Template)
+			if err != nil {
+				return nil, fmt.Errorf("invalid routing template %q: %s", c.Template, err)
+			}
+			r.tmpl = tmpl
+		}
+		rules = append(rules, r)
+	}
+	return &tagRouter{rules: rules}, nil
+}
+
+// Resolve returns the deduplicated, order-preserved routing keys of every
+// rule that matches tags.
+func (t *tagRouter) Resolve(tags map[string]string) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, r := range t.rules {
+		if !r.matches(tags) {
+			continue
+		}
+		key, err := r.resolve(tags)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// routeResult captures the outcome of delivering an alert to a single
+// resolved routing key, so a fanned-out Alert call can report which teams
+// succeeded and which did not.
+type routeResult struct {
+	RoutingKey string
+	Err        error
+}
+
+// routingErrors joins the failed routeResults into a single error, or
+// returns nil if every route succeeded.
+func routingErrors(results []routeResult) error {
+	var msgs []string
+	for _, r := range results {
+		if r.Err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", r.RoutingKey, r.Err))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to deliver to %d of %d routes: %s", len(msgs), len(results), strings.Join(msgs, "; "))
+}
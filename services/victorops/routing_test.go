@@ -0,0 +1,119 @@
+package victorops
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTagRouterResolve_MatchesOnTagAndValue(t *testing.T) {
+	r, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+		{Tag: "team", Value: "web", RoutingKey: "webTeam"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := r.Resolve(map[string]string{"team": "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dbTeam"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestTagRouterResolve_FansOutToEveryMatchingRule(t *testing.T) {
+	r, err := newTagRouter([]RoutingConfig{
+		{Tag: "severity", Value: "critical", RoutingKey: "oncall"},
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := r.Resolve(map[string]string{"severity": "critical", "team": "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"oncall", "dbTeam"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestTagRouterResolve_DeduplicatesRoutingKeys(t *testing.T) {
+	r, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+		{Tag: "escalate", Value: "true", RoutingKey: "dbTeam"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := r.Resolve(map[string]string{"team": "db", "escalate": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dbTeam"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestTagRouterResolve_Template(t *testing.T) {
+	r, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Template: "{{.Tags.team}}-oncall"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := r.Resolve(map[string]string{"team": "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"db-oncall"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestTagRouterResolve_NoMatch(t *testing.T) {
+	r, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := r.Resolve(map[string]string{"team": "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("keys = %v, want none", keys)
+	}
+}
+
+func TestNewTagRouter_InvalidTemplate(t *testing.T) {
+	_, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Template: "{{.Tags.team"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a malformed routing template")
+	}
+}
+
+func TestRoutingErrors(t *testing.T) {
+	if err := routingErrors([]routeResult{{RoutingKey: "a"}, {RoutingKey: "b"}}); err != nil {
+		t.Fatalf("routingErrors() = %v, want nil when every route succeeds", err)
+	}
+
+	err := routingErrors([]routeResult{
+		{RoutingKey: "a"},
+		{RoutingKey: "b", Err: errors.New("resolve failed")},
+	})
+	if err == nil {
+		t.Fatal("expected an error when at least one route fails")
+	}
+}
@@ -2,6 +2,7 @@ package victorops
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,32 +14,163 @@ import (
 	"time"
 
 	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/expvar"
 )
 
 type Service struct {
-	mu         sync.RWMutex
-	enabled    bool
-	routingKey string
-	url        string
-	global     bool
-	logger     *log.Logger
+	mu                   sync.RWMutex
+	enabled              bool
+	routingKey           string
+	url                  string
+	global               bool
+	logger               *log.Logger
+	transport            AlertTransport
+	timeout              time.Duration
+	retryInitialInterval time.Duration
+	retryMultiplier      float64
+	retryMaxElapsed      time.Duration
+
+	batch         bool
+	batchSize     int
+	batchInterval time.Duration
+	queueSize     int
+	overflow      overflowPolicy
+	queue         *queue
+	statMap       *expvar.Map
+
+	metrics      *metricsRegistry
+	httpdService HTTPDService
+
+	routingResolver RoutingKeyResolver
+
+	done     chan struct{}
+	flushNow chan struct{}
+	wg       sync.WaitGroup
 }
 
 func NewService(c Config, l *log.Logger) *Service {
+	overflow, err := parseOverflowPolicy(c.OverflowPolicy)
+	if err != nil {
+		overflow = DropOldest
+	}
+	var resolver RoutingKeyResolver
+	if router, err := newTagRouter(c.Routing); err != nil {
+		l.Println("E! invalid victorops routing config:", err)
+	} else {
+		resolver = router
+	}
 	return &Service{
-		enabled:    c.Enabled,
-		routingKey: c.RoutingKey,
-		url:        c.URL + "/" + c.APIKey + "/",
-		global:     c.Global,
-		logger:     l,
+		enabled:              c.Enabled,
+		routingKey:           c.RoutingKey,
+		url:                  c.URL + "/" + c.APIKey + "/",
+		global:               c.Global,
+		logger:               l,
+		transport:            newHTTPTransport(),
+		timeout:              c.Timeout,
+		retryInitialInterval: c.RetryInitialInterval,
+		retryMultiplier:      c.RetryMultiplier,
+		retryMaxElapsed:      c.RetryMaxElapsed,
+		batch:                c.Batch,
+		batchSize:            c.BatchSize,
+		batchInterval:        c.BatchInterval,
+		queueSize:            c.QueueSize,
+		overflow:             overflow,
+		flushNow:             make(chan struct{}, 1),
+		metrics:              newMetricsRegistry(),
+		routingResolver:      resolver,
 	}
 }
 
+// SetRoutingKeyResolver overrides how alert tags are resolved to routing
+// keys, e.g. to substitute a test double or a resolver built outside of
+// [[victorops.routing]] TOML blocks.
+func (s *Service) SetRoutingKeyResolver(r RoutingKeyResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routingResolver = r
+}
+
+// SetTransport overrides the AlertTransport used to deliver alerts, e.g. to
+// substitute an in-process sink in tests or a gRPC transport in production.
+func (s *Service) SetTransport(t AlertTransport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transport = t
+}
+
 func (s *Service) Open() error {
-	return nil
+	s.mu.Lock()
+	batch := s.batch
+	httpdService := s.httpdService
+	s.mu.Unlock()
+
+	if httpdService != nil {
+		if err := httpdService.AddRoutes(s.routes()); err != nil {
+			return err
+		}
+	}
+
+	if !batch {
+		return nil
+	}
+	return s.startBatching()
 }
 
 func (s *Service) Close() error {
+	s.mu.RLock()
+	httpdService := s.httpdService
+	s.mu.RUnlock()
+
+	if httpdService != nil {
+		httpdService.DelRoutes(s.routes())
+	}
+
+	return s.stopBatching()
+}
+
+// startBatching provisions the queue and starts the background flush
+// goroutine. It is a no-op if batching is already running.
+func (s *Service) startBatching() error {
+	s.mu.Lock()
+	if s.done != nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.queue = newQueue(s.queueSize, s.overflow)
+	s.done = make(chan struct{})
+	interval := s.batchInterval
+	s.mu.Unlock()
+
+	s.registerStats()
+	s.wg.Add(1)
+	go s.flushLoop(interval)
+	return nil
+}
+
+// stopBatching stops the background flush goroutine, flushing whatever is
+// still queued, and tears down the queue. It is a no-op if batching isn't
+// running. The queue is closed before done, so a producer blocked in push
+// under the Block policy observes the closed queue rather than being
+// woken by the final flush's drain and re-queuing into a queue nothing
+// will ever flush again.
+func (s *Service) stopBatching() error {
+	s.mu.Lock()
+	done := s.done
+	q := s.queue
+	s.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	q.close()
+	close(done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.done = nil
+	s.queue = nil
+	s.mu.Unlock()
 	return nil
 }
 
@@ -46,15 +178,41 @@ func (s *Service) Update(newConfig []interface{}) error {
 	if l := len(newConfig); l != 1 {
 		return fmt.Errorf("expected only one new config object, got %d", l)
 	}
-	if c, ok := newConfig[0].(Config); !ok {
+	c, ok := newConfig[0].(Config)
+	if !ok {
 		return fmt.Errorf("expected config object to be of type %T, got %T", c, newConfig[0])
-	} else {
-		s.mu.Lock()
-		s.enabled = c.Enabled
-		s.routingKey = c.RoutingKey
-		s.url = c.URL + "/" + c.APIKey + "/"
-		s.global = c.Global
-		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.enabled = c.Enabled
+	s.routingKey = c.RoutingKey
+	s.url = c.URL + "/" + c.APIKey + "/"
+	s.global = c.Global
+	s.timeout = c.Timeout
+	s.retryInitialInterval = c.RetryInitialInterval
+	s.retryMultiplier = c.RetryMultiplier
+	s.retryMaxElapsed = c.RetryMaxElapsed
+	wasBatch := s.batch
+	s.batch = c.Batch
+	s.batchSize = c.BatchSize
+	s.batchInterval = c.BatchInterval
+	s.queueSize = c.QueueSize
+	if overflow, err := parseOverflowPolicy(c.OverflowPolicy); err == nil {
+		s.overflow = overflow
+	}
+	if router, err := newTagRouter(c.Routing); err == nil {
+		s.routingResolver = router
+	}
+	s.mu.Unlock()
+
+	// Open only ever provisions the queue and flush goroutine once, at
+	// service start, so a live config reload that flips Batch must do the
+	// same provisioning/teardown itself.
+	switch {
+	case c.Batch && !wasBatch:
+		return s.startBatching()
+	case !c.Batch && wasBatch:
+		return s.stopBatching()
 	}
 	return nil
 }
@@ -89,33 +247,140 @@ func (s *Service) Test(options interface{}) error {
 		return fmt.Errorf("unexpected options type %T", options)
 	}
 	return s.Alert(
+		context.Background(),
 		o.RoutingKey,
 		o.MessageType,
 		o.Message,
 		o.EntityID,
 		time.Now(),
 		nil,
+		nil,
 	)
 }
 
-func (s *Service) Alert(routingKey, messageType, message, entityID string, t time.Time, details interface{}) error {
+// retryableError marks an error as transient, meaning the request may
+// succeed if retried (a 5xx response or a network-level failure). Any
+// other error is treated as permanent and returned immediately.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// Alert posts an event to VictorOps. If routingKey is empty and a
+// RoutingKeyResolver is configured, tags is used to resolve one or more
+// routing keys, fanning the event out to each independently. If batching
+// is enabled, each resulting event is instead enqueued and coalesced for a
+// background flush; see Open.
+func (s *Service) Alert(ctx context.Context, routingKey, messageType, message, entityID string, t time.Time, details interface{}, tags map[string]string) error {
+	s.mu.RLock()
+	batch := s.batch
+	resolver := s.routingResolver
+	s.mu.RUnlock()
+
+	keys := []string{routingKey}
+	if routingKey == "" && resolver != nil {
+		resolved, err := resolver.Resolve(tags)
+		if err != nil {
+			return err
+		}
+		if len(resolved) > 0 {
+			keys = resolved
+		}
+	}
+
+	deliver := s.sendAlert
+	if batch {
+		deliver = s.enqueue
+	}
+
+	if len(keys) == 1 {
+		return deliver(ctx, keys[0], messageType, message, entityID, t, details)
+	}
+
+	results := make([]routeResult, len(keys))
+	for i, key := range keys {
+		results[i] = routeResult{RoutingKey: key, Err: deliver(ctx, key, messageType, message, entityID, t, details)}
+	}
+	return routingErrors(results)
+}
+
+// sendAlert posts a single event to VictorOps, retrying with exponential
+// backoff on 5xx responses and network errors, honoring ctx for
+// cancellation and deadlines between attempts. It bypasses batching, and is
+// used both for unbatched Alert calls and to flush queued events.
+func (s *Service) sendAlert(ctx context.Context, routingKey, messageType, message, entityID string, t time.Time, details interface{}) error {
 	url, post, err := s.preparePost(routingKey, messageType, message, entityID, t, details)
 	if err != nil {
 		return err
 	}
+	if routingKey == "" {
+		s.mu.RLock()
+		routingKey = s.routingKey
+		s.mu.RUnlock()
+	}
+	var payload bytes.Buffer
+	if post != nil {
+		if _, err := payload.ReadFrom(post); err != nil {
+			return err
+		}
+	}
+
+	s.mu.RLock()
+	transport := s.transport
+	timeout := s.timeout
+	b := newBackoff(s.retryInitialInterval, s.retryMultiplier, s.retryMaxElapsed)
+	s.mu.RUnlock()
+
+	for {
+		attemptCtx := ctx
+		cancel := func() {}
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		start := time.Now()
+		statusCode, err := s.send(attemptCtx, transport, url, bytes.NewReader(payload.Bytes()))
+		cancel()
+		s.metrics.observe(routingKey, messageType, time.Since(start), statusCode)
+		if err == nil {
+			return nil
+		}
+		retryable, ok := err.(*retryableError)
+		if !ok {
+			return err
+		}
+
+		wait := b.next()
+		if wait == stopBackoff {
+			return retryable.err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
 
-	resp, err := http.Post(url, "application/json", post)
+// send performs a single POST attempt through transport, classifying 5xx
+// responses and transport-level errors as retryable. The returned
+// statusCode is 0 for a transport-level failure, for metrics purposes.
+func (s *Service) send(ctx context.Context, transport AlertTransport, url string, post io.Reader) (statusCode int, err error) {
+	resp, err := transport.Send(ctx, url, post, nil)
 	if err != nil {
-		return err
+		return 0, &retryableError{err}
 	}
 	defer resp.Body.Close()
+	statusCode = resp.StatusCode
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == http.StatusNotFound {
-			return errors.New("URL or API key not found: 404")
+			return statusCode, errors.New("URL or API key not found: 404")
 		}
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return err
+			return statusCode, err
 		}
 		type response struct {
 			Message string `json:"message"`
@@ -124,9 +389,13 @@ func (s *Service) Alert(routingKey, messageType, message, entityID string, t tim
 		b := bytes.NewReader(body)
 		dec := json.NewDecoder(b)
 		dec.Decode(r)
-		return errors.New(r.Message)
+		msg := errors.New(r.Message)
+		if resp.StatusCode >= 500 {
+			return statusCode, &retryableError{msg}
+		}
+		return statusCode, msg
 	}
-	return nil
+	return statusCode, nil
 }
 
 func (s *Service) preparePost(routingKey, messageType, message, entityID string, t time.Time, details interface{}) (string, io.Reader, error) {
@@ -163,3 +432,88 @@ func (s *Service) preparePost(routingKey, messageType, message, entityID string,
 	}
 	return s.url + routingKey, &post, nil
 }
+
+// enqueue buffers e for a later batched flush instead of posting it
+// immediately, coalescing with any already-queued event for the same
+// entityID and routing key. ctx is accepted only so enqueue shares sendAlert's signature.
+func (s *Service) enqueue(ctx context.Context, routingKey, messageType, message, entityID string, t time.Time, details interface{}) error {
+	s.mu.RLock()
+	if !s.enabled {
+		s.mu.RUnlock()
+		return errors.New("service is not enabled")
+	}
+	if routingKey == "" {
+		routingKey = s.routingKey
+	}
+	batchSize := s.batchSize
+	q := s.queue
+	s.mu.RUnlock()
+
+	if q == nil {
+		// Batch was toggled on by Update but startBatching hasn't provisioned
+		// the queue yet, or it was toggled off again in between; report an
+		// error for the event rather than panic.
+		return errors.New("victorops: batching is enabled but the queue is not running")
+	}
+
+	dropped := q.push(&alertEvent{
+		routingKey:  routingKey,
+		messageType: messageType,
+		message:     message,
+		entityID:    entityID,
+		t:           t,
+		details:     details,
+	})
+	if dropped {
+		s.incDropped(1)
+		return nil
+	}
+	s.incQueued(1)
+
+	if batchSize > 0 && q.len() >= batchSize {
+		select {
+		case s.flushNow <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// flushLoop periodically drains the queue, flushing early when signalled
+// by enqueue reaching BatchSize. It runs until stopBatching is called, by
+// Close or by an Update that turns Batch off.
+func (s *Service) flushLoop(interval time.Duration) {
+	defer s.wg.Done()
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			s.flush()
+			return
+		case <-ticker.C:
+			s.flush()
+		case <-s.flushNow:
+			s.flush()
+		}
+	}
+}
+
+// flush drains the queue and sends each coalesced event individually, since
+// the VictorOps API has no batch-POST endpoint.
+func (s *Service) flush() {
+	events := s.queue.drain()
+	if len(events) == 0 {
+		return
+	}
+	s.incFlushed(int64(len(events)))
+	for _, e := range events {
+		if err := s.sendAlert(context.Background(), e.routingKey, e.messageType, e.message, e.entityID, e.t, e.details); err != nil && s.logger != nil {
+			s.logger.Println("E! failed to flush batched victorops alert:", err)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package victorops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResponse is one scripted reply for fakeTransport: either a status
+// code or a transport-level error, never both.
+type fakeResponse struct {
+	statusCode int
+	err        error
+}
+
+// fakeTransport is an in-process AlertTransport that records every payload
+// it receives and returns a fixed status code, so Alert/Test can be
+// exercised without an HTTP round-tripper. If sequence is set, each Send
+// call consumes the next scripted fakeResponse in order, holding on the
+// last one once exhausted, so a test can script a failing-then-recovering
+// (or always-failing) transport.
+type fakeTransport struct {
+	mu         sync.Mutex
+	statusCode int
+	sendErr    error
+	sequence   []fakeResponse
+	attempts   int
+	payloads   []string
+}
+
+func newFakeTransport(statusCode int) *fakeTransport {
+	return &fakeTransport{statusCode: statusCode}
+}
+
+func newSequencedFakeTransport(sequence ...fakeResponse) *fakeTransport {
+	return &fakeTransport{sequence: sequence}
+}
+
+func (f *fakeTransport) Send(ctx context.Context, endpoint string, payload io.Reader, headers http.Header) (*http.Response, error) {
+	f.mu.Lock()
+	resp := fakeResponse{statusCode: f.statusCode, err: f.sendErr}
+	if len(f.sequence) > 0 {
+		idx := f.attempts
+		if idx >= len(f.sequence) {
+			idx = len(f.sequence) - 1
+		}
+		resp = f.sequence[idx]
+	}
+	f.attempts++
+	f.mu.Unlock()
+
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	b, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.payloads = append(f.payloads, string(b))
+	f.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: resp.statusCode,
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}, nil
+}
+
+func (f *fakeTransport) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.payloads)
+}
+
+func (f *fakeTransport) attemptCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.attempts
+}
+
+func testLogger() *log.Logger {
+	return log.New(ioutil.Discard, "", 0)
+}
+
+func TestAlert_UnbatchedDeliversImmediately(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	s := NewService(c, testLogger())
+	transport := newFakeTransport(http.StatusOK)
+	s.SetTransport(transport)
+
+	if err := s.Alert(context.Background(), "teamA", "CRITICAL", "disk full", "host1", time.Now(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.count(); got != 1 {
+		t.Fatalf("transport received %d payloads, want 1", got)
+	}
+}
+
+func TestAlert_FansOutToResolvedRoutingKeys(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	s := NewService(c, testLogger())
+	transport := newFakeTransport(http.StatusOK)
+	s.SetTransport(transport)
+
+	router, err := newTagRouter([]RoutingConfig{
+		{Tag: "severity", Value: "critical", RoutingKey: "oncall"},
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetRoutingKeyResolver(router)
+
+	err = s.Alert(context.Background(), "", "CRITICAL", "disk full", "host1", time.Now(), nil,
+		map[string]string{"severity": "critical", "team": "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.count(); got != 2 {
+		t.Fatalf("transport received %d payloads, want 2 (one per resolved routing key)", got)
+	}
+}
+
+func TestAlert_Batched_FanOutDoesNotCollapse(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	c.Batch = true
+	c.QueueSize = 10
+	s := NewService(c, testLogger())
+	s.SetTransport(newFakeTransport(http.StatusOK))
+
+	router, err := newTagRouter([]RoutingConfig{
+		{Tag: "team", Value: "db", RoutingKey: "dbTeam"},
+		{Tag: "team", Value: "db", RoutingKey: "secondDbTeam", Template: "{{.Tags.team}}-secondary"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetRoutingKeyResolver(router)
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.Alert(context.Background(), "", "CRITICAL", "disk full", "host1", time.Now(), nil,
+		map[string]string{"team": "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.queue.len(); got != 2 {
+		t.Fatalf("queue.len() = %d, want 2 (fan-out must not collapse under batching)", got)
+	}
+}
+
+func TestTest_DeliversViaConfiguredTransport(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	c.RoutingKey = "teamA"
+	s := NewService(c, testLogger())
+	transport := newFakeTransport(http.StatusOK)
+	s.SetTransport(transport)
+
+	if err := s.Test(s.TestOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.count(); got != 1 {
+		t.Fatalf("transport received %d payloads, want 1", got)
+	}
+}
+
+func TestUpdate_TogglingBatchOnProvisionsQueue(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	c.Batch = false
+	s := NewService(c, testLogger())
+	s.SetTransport(newFakeTransport(http.StatusOK))
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c.Batch = true
+	if err := s.Update([]interface{}{c}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Alert(context.Background(), "teamA", "CRITICAL", "disk full", "host1", time.Now(), nil, nil); err != nil {
+		t.Fatalf("Alert after Update toggled Batch on: %v", err)
+	}
+}
+
+func TestUpdate_TogglingBatchOffStopsQueue(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	c.Batch = true
+	s := NewService(c, testLogger())
+	s.SetTransport(newFakeTransport(http.StatusOK))
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c.Batch = false
+	if err := s.Update([]interface{}{c}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.RLock()
+	q := s.queue
+	done := s.done
+	s.mu.RUnlock()
+	if q != nil || done != nil {
+		t.Fatal("Update toggling Batch off should tear down the queue and flush goroutine")
+	}
+}
+
+// newRetryTestService returns a Service configured for fast, deterministic
+// retry tests: a short initial interval with no multiplier growth, so the
+// jittered wait between attempts stays small and bounded.
+func newRetryTestService(maxElapsed time.Duration) *Service {
+	c := NewConfig()
+	c.Enabled = true
+	c.APIKey = "key"
+	c.RetryInitialInterval = 2 * time.Millisecond
+	c.RetryMultiplier = 1
+	c.RetryMaxElapsed = maxElapsed
+	return NewService(c, testLogger())
+}
+
+func TestSendAlert_RetriesOn5xxThenSucceeds(t *testing.T) {
+	s := newRetryTestService(time.Second)
+	transport := newSequencedFakeTransport(
+		fakeResponse{statusCode: http.StatusServiceUnavailable},
+		fakeResponse{statusCode: http.StatusServiceUnavailable},
+		fakeResponse{statusCode: http.StatusOK},
+	)
+	s.SetTransport(transport)
+
+	err := s.sendAlert(context.Background(), "teamA", "CRITICAL", "disk full", "host1", time.Now(), nil)
+	if err != nil {
+		t.Fatalf("sendAlert() = %v, want nil after retries succeed", err)
+	}
+	if got := transport.attemptCount(); got != 3 {
+		t.Fatalf("attemptCount() = %d, want 3 (two 5xx retries then a success)", got)
+	}
+}
+
+func TestSendAlert_RetriesOnNetworkErrorThenSucceeds(t *testing.T) {
+	s := newRetryTestService(time.Second)
+	transport := newSequencedFakeTransport(
+		fakeResponse{err: errors.New("connection refused")},
+		fakeResponse{statusCode: http.StatusOK},
+	)
+	s.SetTransport(transport)
+
+	err := s.sendAlert(context.Background(), "teamA", "CRITICAL", "disk full", "host1", time.Now(), nil)
+	if err != nil {
+		t.Fatalf("sendAlert() = %v, want nil after retries succeed", err)
+	}
+	if got := transport.attemptCount(); got != 2 {
+		t.Fatalf("attemptCount() = %d, want 2 (one network-level failure then a success)", got)
+	}
+}
+
+func TestSendAlert_DoesNotRetryOn4xx(t *testing.T) {
+	s := newRetryTestService(time.Second)
+	transport := newSequencedFakeTransport(fakeResponse{statusCode: http.StatusBadRequest})
+	s.SetTransport(transport)
+
+	err := s.sendAlert(context.Background(), "teamA", "CRITICAL", "disk full", "host1", time.Now(), nil)
+	if err == nil {
+		t.Fatal("sendAlert() = nil, want an error for a 4xx response")
+	}
+	if got := transport.attemptCount(); got != 1 {
+		t.Fatalf("attemptCount() = %d, want 1 (a 4xx must not be retried)", got)
+	}
+}
+
+func TestSendAlert_ContextCancellationCutsRetriesShort(t *testing.T) {
+	s := newRetryTestService(5 * time.Second)
+	s.retryInitialInterval = 50 * time.Millisecond
+	transport := newFakeTransport(http.StatusServiceUnavailable)
+	s.SetTransport(transport)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := s.sendAlert(ctx, "teamA", "CRITICAL", "disk full", "host1", time.Now(), nil)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("sendAlert() = nil, want an error once the context is cancelled")
+	}
+	if elapsed > time.Second {
+		t.Fatalf("sendAlert() took %v after ctx cancellation, want well under the 5s RetryMaxElapsed budget", elapsed)
+	}
+}
@@ -0,0 +1,34 @@
+package victorops
+
+import "github.com/influxdata/kapacitor"
+
+const (
+	statsVictorOpsQueued  = "victorops_queued"
+	statsVictorOpsDropped = "victorops_dropped"
+	statsVictorOpsFlushed = "victorops_flushed"
+)
+
+// registerStats wires the service's batch queue into Kapacitor's internal
+// stats subsystem so cumulative counts of queued, dropped and flushed
+// events show up alongside other service statistics.
+func (s *Service) registerStats() {
+	_, s.statMap = kapacitor.NewStatistics("victorops", map[string]string{})
+}
+
+func (s *Service) incQueued(n int64) {
+	if s.statMap != nil {
+		s.statMap.Add(statsVictorOpsQueued, n)
+	}
+}
+
+func (s *Service) incDropped(n int64) {
+	if s.statMap != nil {
+		s.statMap.Add(statsVictorOpsDropped, n)
+	}
+}
+
+func (s *Service) incFlushed(n int64) {
+	if s.statMap != nil {
+		s.statMap.Add(statsVictorOpsFlushed, n)
+	}
+}
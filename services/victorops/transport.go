@@ -0,0 +1,44 @@
+package victorops
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// AlertTransport abstracts how an alert payload is delivered to a VictorOps
+// (or VictorOps-compatible) endpoint. The default implementation posts JSON
+// over plain HTTP, but an implementation could just as easily stream over
+// gRPC to a local incident aggregator, or collect payloads in-process for
+// tests, without the rest of the service knowing the difference.
+type AlertTransport interface {
+	// Send delivers payload to endpoint and returns the resulting response.
+	// Implementations that do not speak HTTP natively should synthesize an
+	// *http.Response with an appropriate StatusCode.
+	Send(ctx context.Context, endpoint string, payload io.Reader, headers http.Header) (*http.Response, error)
+}
+
+// httpTransport is the default AlertTransport. It posts payload to endpoint
+// as an HTTP request using client.
+type httpTransport struct {
+	client *http.Client
+}
+
+func newHTTPTransport() *httpTransport {
+	return &httpTransport{client: http.DefaultClient}
+}
+
+func (t *httpTransport) Send(ctx context.Context, endpoint string, payload io.Reader, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequest("POST", endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, vs := range headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return t.client.Do(req)
+}